@@ -3,6 +3,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -11,9 +15,11 @@ import (
 )
 
 var (
-	output    = flag.String("output", "", "File to output the diff to")
-	threshold = flag.Float64("threshold", 0.1, "Sensitivity of diff [0, 1]")
-	includeAA = flag.Bool("include-anti-aliasing", false, "Do anti-aliasing detection")
+	output     = flag.String("output", "", "File to output the diff to")
+	threshold  = flag.Float64("threshold", 0.1, "Sensitivity of diff [0, 1]")
+	includeAA  = flag.Bool("include-anti-aliasing", false, "Do anti-aliasing detection")
+	mask       = flag.Bool("mask", false, "Output a transparent diff mask instead of a full diff image")
+	ignoreMask = flag.String("ignore-mask", "", "Path to an image whose non-transparent or non-zero pixels are excluded from comparison")
 )
 
 func abort(format string, vals ...interface{}) {
@@ -46,12 +52,28 @@ func main() {
 		abort("Failed to read %s: %v", flag.Arg(1), err)
 	}
 
-	diff, count, err := pixelmatch.PixelMatch(
-		expected,
-		actual,
+	opts := []pixelmatch.Option{
 		pixelmatch.Threshold(float32(*threshold)),
 		pixelmatch.AntiAliasDetection(*includeAA),
-	)
+	}
+	if *mask {
+		opts = append(opts, pixelmatch.DiffMask(true))
+	}
+	if *ignoreMask != "" {
+		maskFile, err := os.Open(*ignoreMask)
+		if err != nil {
+			abort("Failed to open %s: %v", *ignoreMask, err)
+		}
+		defer maskFile.Close()
+
+		maskImg, _, err := image.Decode(maskFile)
+		if err != nil {
+			abort("Failed to decode %s: %v", *ignoreMask, err)
+		}
+		opts = append(opts, pixelmatch.IgnoreMask(maskImg))
+	}
+
+	diff, count, err := pixelmatch.PixelMatch(actual, expected, opts...)
 	if err != nil {
 		abort("Failed to compare %s and %s: %v", flag.Arg(0), flag.Arg(1), err)
 	}
@@ -0,0 +1,60 @@
+package pixelmatch
+
+// maxYIQDelta is the maximum possible squared perceptual distance between two
+// 8-bit colours in YIQ space, used to scale the threshold option.
+const maxYIQDelta = 35215.0
+
+// blend mixes channel value c with a white background using alpha in [0, 1],
+// where alpha is the proportion of c that is kept.
+func blend(c, alpha float64) float64 {
+	return 255 + (c-255)*alpha
+}
+
+// rgb2y, rgb2i and rgb2q convert a colour given as floating point R, G, B
+// channels (0-255) to the Y, I and Q channels of the YIQ colour space, using
+// the standard NTSC conversion weights.
+func rgb2y(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func rgb2i(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func rgb2q(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+// colorDelta computes the perceptual colour difference between the pixel at
+// index a in pixA and the pixel at index b in pixB. Both images must be
+// non-premultiplied RGBA buffers (as produced by toNRGBA). If yOnly is true,
+// only the luminance (Y channel) delta is returned, which is all the
+// anti-aliasing heuristic needs; otherwise the signed, squared perceptual
+// delta described by the YIQ metric is returned, negative when the first
+// pixel is brighter than the second.
+func colorDelta(pixA, pixB []uint8, a, b int, yOnly bool) float64 {
+	r1, g1, b1, a1 := float64(pixA[a]), float64(pixA[a+1]), float64(pixA[a+2]), float64(pixA[a+3])
+	r2, g2, b2, a2 := float64(pixB[b]), float64(pixB[b+1]), float64(pixB[b+2]), float64(pixB[b+3])
+
+	if a1 == a2 && r1 == r2 && g1 == g2 && b1 == b2 {
+		return 0
+	}
+
+	if a1 < 255 {
+		alpha := a1 / 255
+		r1, g1, b1 = blend(r1, alpha), blend(g1, alpha), blend(b1, alpha)
+	}
+	if a2 < 255 {
+		alpha := a2 / 255
+		r2, g2, b2 = blend(r2, alpha), blend(g2, alpha), blend(b2, alpha)
+	}
+
+	y1, y2 := rgb2y(r1, g1, b1), rgb2y(r2, g2, b2)
+	y := y1 - y2
+
+	if yOnly {
+		return y
+	}
+
+	i := rgb2i(r1, g1, b1) - rgb2i(r2, g2, b2)
+	q := rgb2q(r1, g1, b1) - rgb2q(r2, g2, b2)
+
+	delta := y*y*0.5053 + i*i*0.299 + q*q*0.1957
+
+	if y1 > y2 {
+		return -delta
+	}
+	return delta
+}
@@ -0,0 +1,289 @@
+package pixelmatch
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+)
+
+// toNRGBA returns img as a non-premultiplied RGBA buffer starting at (0, 0),
+// converting and copying it if it isn't already in that form.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min.Eq(image.Point{}) && n.Stride == 4*n.Rect.Dx() {
+		return n
+	}
+
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// drawPixel paints the pixel at pos in pix with colour c, fully opaque.
+func drawPixel(pix []uint8, pos int, c RGB) {
+	pix[pos] = c.R
+	pix[pos+1] = c.G
+	pix[pos+2] = c.B
+	pix[pos+3] = 255
+}
+
+// drawGrayPixel paints the pixel at pos in out with the luminance of the
+// pixel at pos in pix, dimmed towards white by alpha.
+func drawGrayPixel(pix []uint8, pos int, alpha float32, out []uint8) {
+	r, g, b, a := float64(pix[pos]), float64(pix[pos+1]), float64(pix[pos+2]), float64(pix[pos+3])
+	val := uint8(blend(rgb2y(r, g, b), float64(alpha)*a/255))
+	out[pos] = val
+	out[pos+1] = val
+	out[pos+2] = val
+	out[pos+3] = 255
+}
+
+// drawUnchangedPixel paints the pixel at pos in out for a pixel that's
+// considered unchanged: transparent if a diff mask was requested, otherwise
+// the dimmed grayscale of the expected pixel.
+func drawUnchangedPixel(expectedPix []uint8, pos int, cfg config, out []uint8) {
+	if cfg.diffMask {
+		return
+	}
+	drawGrayPixel(expectedPix, pos, cfg.alpha, out)
+}
+
+// isMasked reports whether the pixel at pos in mask excludes its coordinate
+// from comparison: any non-transparent or non-zero channel counts.
+func isMasked(mask *image.NRGBA, pos int) bool {
+	return mask.Pix[pos] != 0 || mask.Pix[pos+1] != 0 || mask.Pix[pos+2] != 0 || mask.Pix[pos+3] != 0
+}
+
+// comparePixels runs the core pixelmatch algorithm over actual and expected,
+// which must already be non-premultiplied RGBA buffers of identical
+// dimensions, and returns the rendered diff image and the number of
+// differing pixels.
+func comparePixels(actual, expected *image.NRGBA, cfg config) (*image.NRGBA, int, error) {
+	actualBounds, expectedBounds := actual.Bounds(), expected.Bounds()
+	if actualBounds.Dx() != expectedBounds.Dx() || actualBounds.Dy() != expectedBounds.Dy() {
+		return nil, 0, fmt.Errorf(
+			"actual and expected images have different dimensions: actual is %dx%d, expected is %dx%d",
+			actualBounds.Dx(), actualBounds.Dy(), expectedBounds.Dx(), expectedBounds.Dy(),
+		)
+	}
+
+	width, height := expectedBounds.Dx(), expectedBounds.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	var ignoreMask *image.NRGBA
+	if cfg.ignoreMask != nil {
+		ignoreMask = toNRGBA(cfg.ignoreMask)
+		maskBounds := ignoreMask.Bounds()
+		if maskBounds.Dx() != width || maskBounds.Dy() != height {
+			return nil, 0, fmt.Errorf(
+				"ignore mask has different dimensions to the compared images: mask is %dx%d, images are %dx%d",
+				maskBounds.Dx(), maskBounds.Dy(), width, height,
+			)
+		}
+	}
+
+	if cfg.region != nil {
+		bounds := image.Rect(0, 0, width, height)
+		if !cfg.region.In(bounds) {
+			return nil, 0, fmt.Errorf("region %v is not within the image bounds %v", *cfg.region, bounds)
+		}
+	}
+
+	workers := cfg.workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > height {
+		workers = height
+	}
+
+	if workers == 1 {
+		count := compareRows(actual, expected, ignoreMask, cfg, out, height, 0, height)
+		return out, count, nil
+	}
+
+	// Split the image into horizontal stripes, one per worker. Each worker
+	// only ever writes into its own non-overlapping rows of out, so no
+	// synchronization is needed there; reads of actual/expected (including
+	// the 3x3 neighbourhood anti-aliasing detection needs) are read-only and
+	// safe to share across goroutines regardless of stripe boundaries.
+	rowsPerWorker := (height + workers - 1) / workers
+	counts := make([]int, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		yStart := w * rowsPerWorker
+		yEnd := minInt(yStart+rowsPerWorker, height)
+		if yStart >= yEnd {
+			continue
+		}
+
+		wg.Add(1)
+		go func(w, yStart, yEnd int) {
+			defer wg.Done()
+			counts[w] = compareRows(actual, expected, ignoreMask, cfg, out, height, yStart, yEnd)
+		}(w, yStart, yEnd)
+	}
+	wg.Wait()
+
+	count := 0
+	for _, c := range counts {
+		count += c
+	}
+
+	return out, count, nil
+}
+
+// compareRows runs the core pixelmatch algorithm over the rows [yStart, yEnd)
+// of actual and expected (which have the given total height), writing into
+// the same rows of out, and returns the number of differing pixels found in
+// that range. The anti-aliasing neighbourhood check may read rows just
+// outside [yStart, yEnd), which is safe since actual/expected are read-only.
+func compareRows(actual, expected, ignoreMask *image.NRGBA, cfg config, out *image.NRGBA, height int, yStart, yEnd int) int {
+	width := expected.Bounds().Dx()
+	maxDelta := maxYIQDelta * float64(cfg.threshold) * float64(cfg.threshold)
+	actualPix, expectedPix := actual.Pix, expected.Pix
+
+	count := 0
+	for y := yStart; y < yEnd; y++ {
+		for x := 0; x < width; x++ {
+			pos := y*actual.Stride + x*4
+
+			if cfg.region != nil && !(image.Point{X: x, Y: y}).In(*cfg.region) {
+				drawUnchangedPixel(expectedPix, pos, cfg, out.Pix)
+				continue
+			}
+
+			if ignoreMask != nil && isMasked(ignoreMask, pos) {
+				// Excluded from comparison entirely: neither counted nor drawn.
+				continue
+			}
+
+			delta := colorDelta(actualPix, expectedPix, pos, pos, false)
+			if math.Abs(delta) <= maxDelta {
+				drawUnchangedPixel(expectedPix, pos, cfg, out.Pix)
+				continue
+			}
+
+			if cfg.antiAliasingDetection &&
+				(antialiased(actualPix, x, y, width, height, expectedPix) ||
+					antialiased(expectedPix, x, y, width, height, actualPix)) {
+				// Anti-aliased pixels aren't counted as differing, so under a
+				// diff mask they stay transparent rather than being painted.
+				if !cfg.diffMask {
+					drawPixel(out.Pix, pos, cfg.antiAliasingColour)
+				}
+				continue
+			}
+
+			colour := cfg.diffColour
+			if alt, err := cfg.diffColourAlt.some(); err == nil && delta > 0 {
+				colour = alt
+			}
+			drawPixel(out.Pix, pos, colour)
+			count++
+		}
+	}
+
+	return count
+}
+
+// antialiased reports whether the pixel at (x1, y1) in pix looks like it is
+// part of an anti-aliased edge: at most two of its 3x3 neighbours share its
+// luminance (the image edge counts as one), it has both a darker and a
+// brighter neighbour, and either its darkest or its brightest neighbour has
+// many siblings (see hasManySiblings) in both pix and otherPix.
+func antialiased(pix []uint8, x1, y1, width, height int, otherPix []uint8) bool {
+	x0, y0 := maxInt(x1-1, 0), maxInt(y1-1, 0)
+	x2, y2 := minInt(x1+1, width-1), minInt(y1+1, height-1)
+	stride := width * 4
+	pos := y1*stride + x1*4
+
+	zeroes := 0
+	if x1 == x0 || x1 == x2 || y1 == y0 || y1 == y2 {
+		zeroes = 1
+	}
+
+	minDelta, maxDelta := 0.0, 0.0
+	var minX, minY, maxX, maxY int
+
+	for x := x0; x <= x2; x++ {
+		for y := y0; y <= y2; y++ {
+			if x == x1 && y == y1 {
+				continue
+			}
+
+			delta := colorDelta(pix, pix, pos, y*stride+x*4, true)
+			if delta == 0 {
+				zeroes++
+				if zeroes > 2 {
+					return false
+				}
+				continue
+			}
+
+			if delta < minDelta {
+				minDelta, minX, minY = delta, x, y
+			}
+			if delta > maxDelta {
+				maxDelta, maxX, maxY = delta, x, y
+			}
+		}
+	}
+
+	if minDelta == 0 || maxDelta == 0 {
+		return false
+	}
+
+	return (hasManySiblings(pix, minX, minY, width, height) && hasManySiblings(otherPix, minX, minY, width, height)) ||
+		(hasManySiblings(pix, maxX, maxY, width, height) && hasManySiblings(otherPix, maxX, maxY, width, height))
+}
+
+// hasManySiblings reports whether the pixel at (x1, y1) in pix has 3 or more
+// identical pixels in its 3x3 neighbourhood (treating the edge of the image
+// as an implicit identical neighbour).
+func hasManySiblings(pix []uint8, x1, y1, width, height int) bool {
+	x0, y0 := maxInt(x1-1, 0), maxInt(y1-1, 0)
+	x2, y2 := minInt(x1+1, width-1), minInt(y1+1, height-1)
+	stride := width * 4
+	pos := y1*stride + x1*4
+
+	zeroes := 0
+	if x1 == x0 || x1 == x2 || y1 == y0 || y1 == y2 {
+		zeroes = 1
+	}
+
+	for x := x0; x <= x2; x++ {
+		for y := y0; y <= y2; y++ {
+			if x == x1 && y == y1 {
+				continue
+			}
+
+			pos2 := y*stride + x*4
+			if pix[pos] == pix[pos2] && pix[pos+1] == pix[pos2+1] && pix[pos+2] == pix[pos2+2] && pix[pos+3] == pix[pos2+3] {
+				zeroes++
+			}
+			if zeroes > 2 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,46 @@
+package pixelmatch
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// benchImage returns a 4K-ish image filled with a gradient, with every
+// third pixel perturbed so the diff pass has real work to do.
+func benchImage(w, h int, perturb bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x + y), A: 255}
+			if perturb && (x+y)%3 == 0 {
+				c.R += 10
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func BenchmarkPixelMatchWorkers(b *testing.B) {
+	const width, height = 3840, 2160
+	actual := toNRGBA(benchImage(width, height, true))
+	expected := toNRGBA(benchImage(width, height, false))
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg, err := parseOptions([]Option{Threshold(0.1), Workers(workers)})
+			if err != nil {
+				b.Fatalf("parseOptions returned error: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := comparePixels(actual, expected, cfg); err != nil {
+					b.Fatalf("comparePixels returned error: %v", err)
+				}
+			}
+		})
+	}
+}
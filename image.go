@@ -0,0 +1,197 @@
+package pixelmatch
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+)
+
+// Format identifies the on-disk encoding of an Image.
+type Format int
+
+const (
+	FormatPNG Format = iota
+	FormatJPEG
+	FormatGIF
+)
+
+// Image wraps a decoded image along with the pixel buffer and format needed
+// to compare it against another Image and re-encode a diff result.
+type Image struct {
+	nrgba  *image.NRGBA
+	format Format
+}
+
+// NewImageFromPath decodes the PNG, JPEG or GIF image at path.
+func NewImageFromPath(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	img, err := NewImageFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// NewImageFromReader decodes a PNG, JPEG or GIF image from r.
+func NewImageFromReader(r io.Reader) (*Image, error) {
+	img, formatName, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	format, err := parseFormat(formatName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{nrgba: toNRGBA(img), format: format}, nil
+}
+
+// NewImageFromImage wraps an already-decoded image.Image, treating it as PNG
+// for the purposes of re-encoding a diff result.
+func NewImageFromImage(img image.Image) *Image {
+	return &Image{nrgba: toNRGBA(img), format: FormatPNG}
+}
+
+func parseFormat(name string) (Format, error) {
+	switch name {
+	case "png":
+		return FormatPNG, nil
+	case "jpeg":
+		return FormatJPEG, nil
+	case "gif":
+		return FormatGIF, nil
+	default:
+		return 0, fmt.Errorf("unsupported image format %q", name)
+	}
+}
+
+// Bounds returns the dimensions of the image.
+func (i *Image) Bounds() image.Rectangle {
+	return i.nrgba.Bounds()
+}
+
+// Compare compares i against other using the given options (or the defaults
+// if opts is nil) and returns the resulting diff.
+func (i *Image) Compare(other *Image, opts *Options) (*DiffResult, error) {
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	cfg, err := parseOptions(opts.options)
+	if err != nil {
+		return nil, err
+	}
+
+	diffImg, count, err := comparePixels(i.nrgba, other.nrgba, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := diffImg.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	percentage := 0.0
+	if total > 0 {
+		percentage = float64(count) / float64(total) * 100
+	}
+
+	format := i.format
+	if opts.encodeFormat != nil {
+		format = *opts.encodeFormat
+	}
+
+	return &DiffResult{
+		DiffCount:      count,
+		DiffPercentage: percentage,
+		Image:          diffImg,
+		format:         format,
+	}, nil
+}
+
+// DiffResult is the outcome of comparing two Images.
+type DiffResult struct {
+	// DiffCount is the number of differing pixels.
+	DiffCount int
+	// DiffPercentage is DiffCount as a percentage of the total pixel count.
+	DiffPercentage float64
+	// Image is the rendered diff image.
+	Image  image.Image
+	format Format
+}
+
+// Encode writes the diff image to w, in the format of the Image that
+// produced this result unless overridden via Options.SetEncodeFormat.
+func (d *DiffResult) Encode(w io.Writer) error {
+	switch d.format {
+	case FormatJPEG:
+		return jpeg.Encode(w, d.Image, nil)
+	case FormatGIF:
+		return gif.Encode(w, d.Image, nil)
+	default:
+		return png.Encode(w, d.Image)
+	}
+}
+
+// Options is a mutable, reusable collection of PixelMatch options, as an
+// alternative to passing functional options directly.
+type Options struct {
+	options      []Option
+	encodeFormat *Format
+}
+
+// NewOptions returns an Options value initialised to the PixelMatch defaults.
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// SetThreshold sets the matching threshold. See Threshold.
+func (o *Options) SetThreshold(threshold float32) *Options {
+	o.options = append(o.options, Threshold(threshold))
+	return o
+}
+
+// SetAlpha sets the opacity of the original image in the diff output. See Alpha.
+func (o *Options) SetAlpha(alpha float32) *Options {
+	o.options = append(o.options, Alpha(alpha))
+	return o
+}
+
+// SetDiffColor sets the colour of different pixels in the output. See DiffColour.
+func (o *Options) SetDiffColor(rgb RGB) *Options {
+	o.options = append(o.options, DiffColour(rgb))
+	return o
+}
+
+// SetAntiAliasColor sets the colour of anti-aliasing pixels in the output. See AntiAliasColour.
+func (o *Options) SetAntiAliasColor(rgb RGB) *Options {
+	o.options = append(o.options, AntiAliasColour(rgb))
+	return o
+}
+
+// SetAntiAliasDetection sets whether to include anti-aliasing detection. See AntiAliasDetection.
+func (o *Options) SetAntiAliasDetection(enable bool) *Options {
+	o.options = append(o.options, AntiAliasDetection(enable))
+	return o
+}
+
+// SetDiffMask sets whether the output diff image should be a transparent mask. See DiffMask.
+func (o *Options) SetDiffMask(enable bool) *Options {
+	o.options = append(o.options, DiffMask(enable))
+	return o
+}
+
+// SetEncodeFormat overrides the format Encode will use, instead of the
+// format of the Image that produced the DiffResult.
+func (o *Options) SetEncodeFormat(format Format) *Options {
+	o.encodeFormat = &format
+	return o
+}
@@ -0,0 +1,62 @@
+package pixelmatch
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImageCompare(t *testing.T) {
+	expected := solidImage(4, 4, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual := solidImage(4, 4, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual.SetRGBA(1, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	actualImg := NewImageFromImage(actual)
+	expectedImg := NewImageFromImage(expected)
+
+	result, err := actualImg.Compare(expectedImg, NewOptions().SetThreshold(0.1))
+	if err != nil {
+		t.Fatalf("Compare returned error: %v", err)
+	}
+	if result.DiffCount != 1 {
+		t.Errorf("got DiffCount %d, want 1", result.DiffCount)
+	}
+	wantPercentage := 100.0 / 16.0
+	if result.DiffPercentage != wantPercentage {
+		t.Errorf("got DiffPercentage %v, want %v", result.DiffPercentage, wantPercentage)
+	}
+
+	var buf bytes.Buffer
+	if err := result.Encode(&buf); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Error("expected Encode to write a non-empty image")
+	}
+}
+
+func TestImageCompareDefaultOptions(t *testing.T) {
+	img := solidImage(3, 3, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	actualImg := NewImageFromImage(img)
+	expectedImg := NewImageFromImage(img)
+
+	result, err := actualImg.Compare(expectedImg, nil)
+	if err != nil {
+		t.Fatalf("Compare returned error: %v", err)
+	}
+	if result.DiffCount != 0 {
+		t.Errorf("got DiffCount %d, want 0", result.DiffCount)
+	}
+}
+
+func TestNewImageFromReader(t *testing.T) {
+	img := solidImage(2, 2, color.RGBA{R: 5, G: 6, B: 7, A: 255})
+	loaded, err := NewImageFromReader(bytes.NewReader(encodePNG(t, img)))
+	if err != nil {
+		t.Fatalf("NewImageFromReader returned error: %v", err)
+	}
+	if loaded.Bounds() != (image.Rectangle{Max: image.Point{X: 2, Y: 2}}) {
+		t.Errorf("got bounds %v, want 2x2 image at origin", loaded.Bounds())
+	}
+}
@@ -1,8 +1,11 @@
 package pixelmatch
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"image"
+	"runtime"
 )
 
 var (
@@ -20,6 +23,10 @@ const (
 	antiAliasColorKey
 	diffColourKey
 	diffColourAltKey
+	diffMaskKey
+	ignoreMaskKey
+	workersKey
+	regionKey
 )
 
 // RGB is a representation of an RGB colour with R, G, B mapping to the red
@@ -35,7 +42,7 @@ type optionalRGB struct {
 }
 
 // Generic option. The effect of providing this option is defined by its name and value.
-type option struct {
+type Option struct {
 	// Key uniquely identifying the option. This defines what the option is
 	// about (e.g. threshold).
 	key optionKey
@@ -56,36 +63,69 @@ type config struct {
 	antiAliasingColour    RGB
 	diffColour            RGB
 	diffColourAlt         optionalRGB
+	diffMask              bool
+	ignoreMask            image.Image
+	workers               int
+	region                *image.Rectangle
 }
 
 // Threshold specifies the matching threshold where a smaller value means more sensitive.
-func Threshold(threshold float32) option {
-	return option{key: thresholdKey, value: threshold}
+func Threshold(threshold float32) Option {
+	return Option{key: thresholdKey, value: threshold}
 }
 
 // AntiAliasDetection specifies whether to include anti-aliasing detection.
-func AntiAliasDetection(enable bool) option {
-	return option{key: antiAliasDetectionKey, value: enable}
+func AntiAliasDetection(enable bool) Option {
+	return Option{key: antiAliasDetectionKey, value: enable}
 }
 
 // Alpha specifies the opacity of the original image in the diff output.
-func Alpha(alpha float32) option {
-	return option{key: alphaKey, value: alpha}
+func Alpha(alpha float32) Option {
+	return Option{key: alphaKey, value: alpha}
 }
 
 // AntiAliasColour specifies the colour of anti-aliasing pixels in the output.
-func AntiAliasColour(rgb RGB) option {
-	return option{key: antiAliasColorKey, value: rgb}
+func AntiAliasColour(rgb RGB) Option {
+	return Option{key: antiAliasColorKey, value: rgb}
 }
 
 // DiffColour specifies the colour of different pixels in the output.
-func DiffColour(rgb RGB) option {
-	return option{key: diffColourKey, value: rgb}
+func DiffColour(rgb RGB) Option {
+	return Option{key: diffColourKey, value: rgb}
 }
 
 // DiffColourAlt specifies the colour the differentiate between dark on light differences.
-func DiffColourAlt(rgb RGB) option {
-	return option{key: diffColourAltKey, value: rgb}
+func DiffColourAlt(rgb RGB) Option {
+	return Option{key: diffColourAltKey, value: rgb}
+}
+
+// DiffMask specifies whether the output diff image should be fully
+// transparent except at differing pixels, suitable for overlaying on top of
+// one of the source images.
+func DiffMask(enable bool) Option {
+	return Option{key: diffMaskKey, value: enable}
+}
+
+// IgnoreMask specifies a same-sized mask image; any coordinate where the mask
+// has a non-transparent or non-zero pixel is excluded from comparison
+// entirely, letting callers focus a comparison on a region of interest.
+func IgnoreMask(mask image.Image) Option {
+	return Option{key: ignoreMaskKey, value: mask}
+}
+
+// Workers specifies how many goroutines to split the pixel comparison across.
+// It defaults to runtime.NumCPU(); 1 disables parallelism entirely.
+func Workers(n int) Option {
+	return Option{key: workersKey, value: n}
+}
+
+// Region restricts comparison and diff output to a sub-rectangle of the
+// images, both of which must contain it within their bounds. Pixels outside
+// the region are rendered the same way as unchanged pixels and are never
+// counted as differing, letting callers target a known-changing area (e.g. a
+// chart widget) without false positives from surrounding chrome.
+func Region(r image.Rectangle) Option {
+	return Option{key: regionKey, value: r}
 }
 
 // PixelMatch compares two images, given as bytes and returns an image (in
@@ -102,15 +142,32 @@ func DiffColourAlt(rgb RGB) option {
 // - AntiAliasColour = 255, 255, 0 (yellow)
 // - DiffColour  = 255, 0, 0 (red)
 // - DiffColourAlg = none
-func PixelMatch(actual, expected []byte, options ...option) ([]byte, int, error) {
-	config, err := parseOptions(options)
+func PixelMatch(actual, expected []byte, options ...Option) ([]byte, int, error) {
+	actualImg, err := NewImageFromReader(bytes.NewReader(actual))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode actual image: %w", err)
+	}
+	expectedImg, err := NewImageFromReader(bytes.NewReader(expected))
 	if err != nil {
-		return []byte{}, 0, err
+		return nil, 0, fmt.Errorf("failed to decode expected image: %w", err)
 	}
 
-	_ = config
+	// PixelMatch has always produced a PNG-encoded diff, regardless of the
+	// format of its inputs, so pin the encode format rather than deferring to
+	// actualImg's format.
+	opts := (&Options{options: options}).SetEncodeFormat(FormatPNG)
 
-	return []byte{}, 0, ErrNotImplemented
+	result, err := actualImg.Compare(expectedImg, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buf bytes.Buffer
+	if err := result.Encode(&buf); err != nil {
+		return nil, 0, fmt.Errorf("failed to encode diff image: %w", err)
+	}
+
+	return buf.Bytes(), result.DiffCount, nil
 }
 
 func noneRGB() optionalRGB {
@@ -142,7 +199,7 @@ func (r optionalRGB) some() (RGB, error) {
 
 // Parse the list of type-unsafe options into a type-safe config. If there are
 // any any issue with types or such, returns a descriptive error.
-func parseOptions(options []option) (config, error) {
+func parseOptions(options []Option) (config, error) {
 	config := config{
 		threshold:             0.1,
 		antiAliasingDetection: false,
@@ -150,6 +207,7 @@ func parseOptions(options []option) (config, error) {
 		antiAliasingColour:    RGB{255, 255, 0},
 		diffColour:            RGB{255, 0, 0},
 		diffColourAlt:         noneRGB(),
+		workers:               runtime.NumCPU(),
 	}
 	for _, opt := range options {
 		switch opt.key {
@@ -198,7 +256,31 @@ func parseOptions(options []option) (config, error) {
 				return config, fmt.Errorf("invalid diff colour alt value %v, expected type RGB", opt.value)
 			}
 			config.diffColourAlt = someRGB(diffColourAlt)
+		case diffMaskKey:
+			diffMask, ok := opt.value.(bool)
+			if !ok {
+				return config, fmt.Errorf("invalid diff mask value %v, expected type bool", opt.value)
+			}
+			config.diffMask = diffMask
+		case ignoreMaskKey:
+			ignoreMask, ok := opt.value.(image.Image)
+			if !ok {
+				return config, fmt.Errorf("invalid ignore mask value %v, expected type image.Image", opt.value)
+			}
+			config.ignoreMask = ignoreMask
+		case workersKey:
+			workers, ok := opt.value.(int)
+			if !ok {
+				return config, fmt.Errorf("invalid workers value %v, expected type int", opt.value)
+			}
+			config.workers = workers
+		case regionKey:
+			region, ok := opt.value.(image.Rectangle)
+			if !ok {
+				return config, fmt.Errorf("invalid region value %v, expected type image.Rectangle", opt.value)
+			}
+			config.region = &region
 		}
 	}
-	return config, ErrNotImplemented
+	return config, nil
 }
@@ -0,0 +1,295 @@
+package pixelmatch
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// solidImage returns a w x h RGBA image filled with c.
+func solidImage(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPixelMatch_IdenticalImages(t *testing.T) {
+	img := solidImage(10, 10, color.RGBA{R: 100, G: 150, B: 200, A: 255})
+	actual := encodePNG(t, img)
+	expected := encodePNG(t, img)
+
+	_, count, err := PixelMatch(actual, expected)
+	if err != nil {
+		t.Fatalf("PixelMatch returned error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 differing pixels, got %d", count)
+	}
+}
+
+func TestPixelMatch_SinglePixelDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float32
+		wantCount int
+	}{
+		{name: "default threshold catches a mild diff", threshold: 0.1, wantCount: 1},
+		{name: "high threshold tolerates the same diff", threshold: 0.9, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectedImg := solidImage(5, 5, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+			actualImg := solidImage(5, 5, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+			actualImg.SetRGBA(2, 2, color.RGBA{R: 120, G: 120, B: 120, A: 255})
+
+			_, count, err := PixelMatch(
+				encodePNG(t, actualImg),
+				encodePNG(t, expectedImg),
+				Threshold(tt.threshold),
+			)
+			if err != nil {
+				t.Fatalf("PixelMatch returned error: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("got %d differing pixels, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPixelMatch_AntiAliasDetection(t *testing.T) {
+	// A vertical black/white edge running beside the differing pixel makes it
+	// look anti-aliased: its brightness sits between two neighbours that are
+	// each part of larger same-coloured regions.
+	build := func() (*image.RGBA, *image.RGBA) {
+		expected := image.NewRGBA(image.Rect(0, 0, 5, 5))
+		actual := image.NewRGBA(image.Rect(0, 0, 5, 5))
+		black := color.RGBA{R: 0, G: 0, B: 0, A: 255}
+		white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		for y := 0; y < 5; y++ {
+			for x := 0; x < 5; x++ {
+				c := black
+				if x >= 2 {
+					c = white
+				}
+				expected.SetRGBA(x, y, c)
+				actual.SetRGBA(x, y, c)
+			}
+		}
+		// Nudge the pixel straddling the edge to a mid-tone, as an
+		// anti-aliased edge would be.
+		actual.SetRGBA(2, 2, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+		return actual, expected
+	}
+
+	t.Run("detection enabled treats the edge pixel as anti-aliased", func(t *testing.T) {
+		actual, expected := build()
+		_, count, err := PixelMatch(
+			encodePNG(t, actual),
+			encodePNG(t, expected),
+			Threshold(0.01),
+			AntiAliasDetection(true),
+		)
+		if err != nil {
+			t.Fatalf("PixelMatch returned error: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("expected anti-aliased pixel to be excluded from the diff count, got %d", count)
+		}
+	})
+
+	t.Run("detection disabled counts the edge pixel as a diff", func(t *testing.T) {
+		actual, expected := build()
+		_, count, err := PixelMatch(
+			encodePNG(t, actual),
+			encodePNG(t, expected),
+			Threshold(0.01),
+			AntiAliasDetection(false),
+		)
+		if err != nil {
+			t.Fatalf("PixelMatch returned error: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("expected 1 differing pixel, got %d", count)
+		}
+	})
+}
+
+// TestPixelMatch_AntiAliasDetection_Asymmetric exercises a pixel whose
+// darkest and brightest neighbours both have many siblings in the actual
+// image, but neither has many siblings in the expected image. The reference
+// algorithm requires each neighbour to have siblings in *both* images, so
+// this must be treated as a real diff rather than anti-aliasing; grouping the
+// sibling checks by image instead of by neighbour would wrongly suppress it.
+func TestPixelMatch_AntiAliasDetection_Asymmetric(t *testing.T) {
+	gray := func(v uint8) color.RGBA { return color.RGBA{R: v, G: v, B: v, A: 255} }
+
+	actual := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	actualValues := map[[2]int]uint8{
+		{2, 2}: 128, // pixel under test
+		{1, 1}: 120, {1, 2}: 120, {1, 3}: 120,
+		{2, 1}: 120, {2, 3}: 120,
+		{3, 1}: 0, {3, 2}: 120, {3, 3}: 250,
+		{2, 0}: 0, {3, 0}: 0, {4, 0}: 0, // siblings of the darkest neighbour (3,1)
+		{2, 4}: 250, {3, 4}: 250, {4, 3}: 250, // siblings of the brightest neighbour (3,3)
+	}
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			v, ok := actualValues[[2]int{x, y}]
+			if !ok {
+				v = 200
+			}
+			actual.SetRGBA(x, y, gray(v))
+		}
+	}
+
+	// expected matches actual everywhere except at the pixel under test (to
+	// trigger the diff) and at one sibling of each extreme neighbour (to
+	// break that neighbour's "many siblings" property in expected only).
+	expected := image.NewRGBA(actual.Bounds())
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			expected.SetRGBA(x, y, actual.RGBAAt(x, y))
+		}
+	}
+	expected.SetRGBA(2, 2, gray(255))
+	expected.SetRGBA(3, 0, gray(77))
+	expected.SetRGBA(3, 4, gray(77))
+
+	diff, _, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), Threshold(0.01), AntiAliasDetection(true))
+	if err != nil {
+		t.Fatalf("PixelMatch returned error: %v", err)
+	}
+
+	diffImg, _, err := image.Decode(bytes.NewReader(diff))
+	if err != nil {
+		t.Fatalf("failed to decode diff image: %v", err)
+	}
+
+	wantDiffColour := RGB{R: 255, G: 0, B: 0}
+	r, g, b, _ := diffImg.At(2, 2).RGBA()
+	got := RGB{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8)}
+	if got != wantDiffColour {
+		t.Errorf("pixel (2,2) rendered as %+v, want diff colour %+v (should not be treated as anti-aliased)", got, wantDiffColour)
+	}
+}
+
+func TestPixelMatch_MismatchedDimensions(t *testing.T) {
+	actual := solidImage(10, 10, color.RGBA{A: 255})
+	expected := solidImage(5, 5, color.RGBA{A: 255})
+
+	_, _, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected))
+	if err == nil {
+		t.Fatal("expected an error for mismatched dimensions, got nil")
+	}
+}
+
+func TestPixelMatch_DiffMask(t *testing.T) {
+	expected := solidImage(3, 3, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual := solidImage(3, 3, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual.SetRGBA(1, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	diff, count, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), DiffMask(true))
+	if err != nil {
+		t.Fatalf("PixelMatch returned error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("got %d differing pixels, want 1", count)
+	}
+
+	diffImg, _, err := image.Decode(bytes.NewReader(diff))
+	if err != nil {
+		t.Fatalf("failed to decode diff image: %v", err)
+	}
+	_, _, _, a := diffImg.At(0, 0).RGBA()
+	if a != 0 {
+		t.Errorf("expected unchanged pixel to be fully transparent, got alpha %d", a)
+	}
+	_, _, _, a = diffImg.At(1, 1).RGBA()
+	if a == 0 {
+		t.Errorf("expected differing pixel to be opaque, got alpha %d", a)
+	}
+}
+
+func TestPixelMatch_Workers(t *testing.T) {
+	expected := solidImage(20, 20, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual := solidImage(20, 20, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	for y := 0; y < 20; y += 4 {
+		actual.SetRGBA(5, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	}
+
+	_, serialCount, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), Workers(1))
+	if err != nil {
+		t.Fatalf("PixelMatch returned error: %v", err)
+	}
+
+	for _, workers := range []int{2, 4, 8} {
+		_, count, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), Workers(workers))
+		if err != nil {
+			t.Fatalf("PixelMatch with %d workers returned error: %v", workers, err)
+		}
+		if count != serialCount {
+			t.Errorf("workers=%d got %d differing pixels, want %d (serial)", workers, count, serialCount)
+		}
+	}
+}
+
+func TestPixelMatch_Region(t *testing.T) {
+	expected := solidImage(4, 4, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual := solidImage(4, 4, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	// One diff inside the region, one outside it.
+	actual.SetRGBA(1, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	actual.SetRGBA(3, 3, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	region := image.Rect(0, 0, 2, 2)
+	_, count, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), Region(region))
+	if err != nil {
+		t.Fatalf("PixelMatch returned error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d differing pixels, want 1 (only the one inside the region)", count)
+	}
+}
+
+func TestPixelMatch_RegionOutOfBounds(t *testing.T) {
+	expected := solidImage(4, 4, color.RGBA{A: 255})
+	actual := solidImage(4, 4, color.RGBA{A: 255})
+
+	_, _, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), Region(image.Rect(0, 0, 10, 10)))
+	if err == nil {
+		t.Fatal("expected an error for a region outside the image bounds, got nil")
+	}
+}
+
+func TestPixelMatch_IgnoreMask(t *testing.T) {
+	expected := solidImage(3, 3, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual := solidImage(3, 3, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	actual.SetRGBA(1, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	mask := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	mask.SetRGBA(1, 1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	_, count, err := PixelMatch(encodePNG(t, actual), encodePNG(t, expected), IgnoreMask(mask))
+	if err != nil {
+		t.Fatalf("PixelMatch returned error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected masked pixel to be excluded from the diff count, got %d", count)
+	}
+}